@@ -0,0 +1,83 @@
+package v1alpha1
+
+// ArtifactRepository describes a backing object store used to push/pull
+// WorkflowRun artifacts, as an alternative to a shared PVC. Exactly one of
+// the typed sub-configs should be set; the coordinator and resource-resolver
+// containers pick whichever one is non-nil. It lives here, rather than in
+// the controller config package, so a WorkflowRun's spec.artifactRepository
+// can reuse the exact same schema as the cluster-wide and ExecutionContext
+// defaults.
+type ArtifactRepository struct {
+	// S3 configures an S3-compatible object store.
+	S3 *S3ArtifactRepository `json:"s3"`
+	// GCS configures Google Cloud Storage.
+	GCS *GCSArtifactRepository `json:"gcs"`
+	// OSS configures Alibaba Cloud Object Storage Service.
+	OSS *OSSArtifactRepository `json:"oss"`
+	// HTTP configures a plain HTTP(S) artifact server.
+	HTTP *HTTPArtifactRepository `json:"http"`
+	// HDFS configures an HDFS cluster.
+	HDFS *HDFSArtifactRepository `json:"hdfs"`
+}
+
+// SecretKeySelector names a key within a secret, analogous to corev1.SecretKeySelector.
+type SecretKeySelector struct {
+	// Name is the name of the secret.
+	Name string `json:"name"`
+	// Key is the key within the secret's data.
+	Key string `json:"key"`
+}
+
+// S3ArtifactRepository configures an S3-compatible object store.
+type S3ArtifactRepository struct {
+	// Endpoint is the S3 API endpoint, e.g. "s3.amazonaws.com" or a self-hosted
+	// minio address.
+	Endpoint string `json:"endpoint"`
+	// Bucket is the bucket artifacts are stored in.
+	Bucket string `json:"bucket"`
+	// Region is the bucket's region.
+	Region string `json:"region"`
+	// KeyPrefix is prepended to every artifact's object key, useful to share a
+	// bucket across clusters/environments.
+	KeyPrefix string `json:"key_prefix"`
+	// AccessKeySecretRef selects the access key ID from a secret.
+	AccessKeySecretRef SecretKeySelector `json:"access_key_secret_ref"`
+	// SecretKeySecretRef selects the secret access key from a secret.
+	SecretKeySecretRef SecretKeySelector `json:"secret_key_secret_ref"`
+	// Insecure disables TLS verification, for self-signed endpoints.
+	Insecure bool `json:"insecure"`
+}
+
+// GCSArtifactRepository configures Google Cloud Storage.
+type GCSArtifactRepository struct {
+	// Bucket is the bucket artifacts are stored in.
+	Bucket string `json:"bucket"`
+	// ServiceAccountKeySecretRef selects a GCP service account key JSON from a secret.
+	ServiceAccountKeySecretRef SecretKeySelector `json:"service_account_key_secret_ref"`
+}
+
+// OSSArtifactRepository configures Alibaba Cloud OSS.
+type OSSArtifactRepository struct {
+	// Endpoint is the OSS API endpoint.
+	Endpoint string `json:"endpoint"`
+	// Bucket is the bucket artifacts are stored in.
+	Bucket string `json:"bucket"`
+	// AccessKeySecretRef selects the access key ID from a secret.
+	AccessKeySecretRef SecretKeySelector `json:"access_key_secret_ref"`
+	// SecretKeySecretRef selects the secret access key from a secret.
+	SecretKeySecretRef SecretKeySelector `json:"secret_key_secret_ref"`
+}
+
+// HTTPArtifactRepository configures a plain HTTP(S) artifact server.
+type HTTPArtifactRepository struct {
+	// URL is the base URL artifacts are pushed/pulled relative to.
+	URL string `json:"url"`
+}
+
+// HDFSArtifactRepository configures an HDFS cluster.
+type HDFSArtifactRepository struct {
+	// Addresses is the list of HDFS namenode addresses.
+	Addresses []string `json:"addresses"`
+	// Path is the base path artifacts are stored under.
+	Path string `json:"path"`
+}
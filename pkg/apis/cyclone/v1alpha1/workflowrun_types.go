@@ -0,0 +1,83 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// WorkflowRun is a single execution of a Workflow.
+type WorkflowRun struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   WorkflowRunSpec   `json:"spec"`
+	Status WorkflowRunStatus `json:"status"`
+}
+
+// WorkflowRunSpec is the spec of a WorkflowRun.
+type WorkflowRunSpec struct {
+	// WorkflowRef refers to the Workflow this run executes.
+	WorkflowRef string `json:"workflowRef"`
+	// TTLStrategy overrides the cluster-wide TTLDefaults for this WorkflowRun.
+	TTLStrategy *TTLStrategy `json:"ttlStrategy,omitempty"`
+	// Priority controls admission order when the parallelism queue is full;
+	// higher values are more urgent. Defaults to 0.
+	Priority int32 `json:"priority,omitempty"`
+	// ArtifactRepository overrides the ExecutionContext's and cluster-wide
+	// default artifact repository for this WorkflowRun.
+	ArtifactRepository *ArtifactRepository `json:"artifactRepository,omitempty"`
+}
+
+// TTLStrategy defines how long after termination a WorkflowRun should be kept
+// around before being deleted. Nil fields fall back to the cluster-wide
+// TTLDefaults in WorkflowControllerConfig.
+type TTLStrategy struct {
+	// SecondsAfterCompletion is the TTL applied regardless of outcome.
+	SecondsAfterCompletion *int64 `json:"secondsAfterCompletion,omitempty"`
+	// SecondsAfterSuccess is the TTL applied when the WorkflowRun succeeded.
+	SecondsAfterSuccess *int64 `json:"secondsAfterSuccess,omitempty"`
+	// SecondsAfterFailure is the TTL applied when the WorkflowRun failed, errored or was cancelled.
+	SecondsAfterFailure *int64 `json:"secondsAfterFailure,omitempty"`
+}
+
+// WorkflowRunStatus is the status of a WorkflowRun.
+type WorkflowRunStatus struct {
+	// Overall is the overall status of the WorkflowRun.
+	Overall Status `json:"overall"`
+}
+
+// Status represents the status of a WorkflowRun (or a stage within it).
+type Status struct {
+	// Phase is the current phase.
+	Phase Phase `json:"phase"`
+	// LastTransitionTime is the last time Phase changed. For a terminated
+	// WorkflowRun, this is its finish time.
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+}
+
+// Phase is the phase of a WorkflowRun or stage.
+type Phase string
+
+const (
+	// StatusPending means the WorkflowRun has been accepted but hasn't started running.
+	StatusPending Phase = "Pending"
+	// StatusRunning means the WorkflowRun is currently running.
+	StatusRunning Phase = "Running"
+	// StatusSucceeded means the WorkflowRun finished successfully.
+	StatusSucceeded Phase = "Succeeded"
+	// StatusFailed means the WorkflowRun finished with a failure.
+	StatusFailed Phase = "Failed"
+	// StatusError means the WorkflowRun could not complete due to an unexpected error.
+	StatusError Phase = "Error"
+	// StatusCancelled means the WorkflowRun was cancelled before it could finish.
+	StatusCancelled Phase = "Cancelled"
+)
+
+// IsTerminated reports whether the phase is one that won't transition any further.
+func (p Phase) IsTerminated() bool {
+	switch p {
+	case StatusSucceeded, StatusFailed, StatusError, StatusCancelled:
+		return true
+	default:
+		return false
+	}
+}
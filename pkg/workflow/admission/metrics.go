@@ -0,0 +1,58 @@
+package admission
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	queueDepth = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "cyclone",
+		Subsystem: "admission",
+		Name:      "queue_depth",
+		Help:      "Current number of WorkflowRuns waiting in the admission queue.",
+	})
+
+	waitDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "cyclone",
+		Subsystem: "admission",
+		Name:      "wait_duration_seconds",
+		Help:      "Time a WorkflowRun spent waiting in the admission queue before being dequeued.",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 12),
+	})
+
+	evictionsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "cyclone",
+		Subsystem: "admission",
+		Name:      "evictions_total",
+		Help:      "Total number of WorkflowRuns evicted from the waiting queue by a higher priority arrival.",
+	}, []string{"priority_band"})
+
+	enqueuesTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "cyclone",
+		Subsystem: "admission",
+		Name:      "enqueues_total",
+		Help:      "Total number of WorkflowRuns admitted into the waiting queue.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(queueDepth, waitDuration, evictionsTotal, enqueuesTotal)
+}
+
+func observeQueueDepth(depth int64) {
+	queueDepth.Set(float64(depth))
+}
+
+func observeWaitTime(d time.Duration) {
+	waitDuration.Observe(d.Seconds())
+}
+
+func observeEnqueue() {
+	enqueuesTotal.Inc()
+}
+
+func observeEviction(band string) {
+	evictionsTotal.WithLabelValues(band).Inc()
+}
@@ -0,0 +1,224 @@
+// Package admission implements priority-aware admission and scheduling for
+// WorkflowRuns waiting to run: a bounded waiting queue ordered by (priority
+// desc, enqueue time asc), and NamespaceConstraint, which resolves the
+// per-namespace constraint from controller.ParallelismConfig. The package
+// does not count running WorkflowRuns and does not itself enforce
+// MaxParallel.
+package admission
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+
+	"github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
+	"github.com/caicloud/cyclone/pkg/workflow/controller"
+)
+
+// Entry is a single WorkflowRun waiting to be admitted to run.
+type Entry struct {
+	Namespace   string
+	Workflow    string
+	WorkflowRun string
+	Priority    int32
+	EnqueuedAt  time.Time
+
+	index int
+}
+
+// EntryForWorkflowRun builds the waiting-queue Entry for a WorkflowRun,
+// taking its Priority from spec.priority (0 if unset, same as any other
+// WorkflowRun that doesn't configure it).
+func EntryForWorkflowRun(wfr *v1alpha1.WorkflowRun) *Entry {
+	return &Entry{
+		Namespace:   wfr.Namespace,
+		Workflow:    wfr.Spec.WorkflowRef,
+		WorkflowRun: wfr.Name,
+		Priority:    wfr.Spec.Priority,
+	}
+}
+
+// entryHeap is a container/heap.Interface ordering Entries by priority
+// descending, breaking ties by enqueue time ascending (oldest first).
+type entryHeap []*Entry
+
+// higherPriority reports whether a should be admitted before b: higher
+// Priority wins, ties broken by earlier EnqueuedAt.
+func higherPriority(a, b *Entry) bool {
+	if a.Priority != b.Priority {
+		return a.Priority > b.Priority
+	}
+	return a.EnqueuedAt.Before(b.EnqueuedAt)
+}
+
+func (h entryHeap) Len() int           { return len(h) }
+func (h entryHeap) Less(i, j int) bool { return higherPriority(h[i], h[j]) }
+func (h entryHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+func (h *entryHeap) Push(x interface{}) {
+	e := x.(*Entry)
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+func (h *entryHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	e := old[n-1]
+	old[n-1] = nil
+	e.index = -1
+	*h = old[:n-1]
+	return e
+}
+
+// EvictionReason is recorded on a WorkflowRun that was evicted from a
+// waiting queue to make room for a higher priority one.
+const EvictionReason = "Evicted from waiting queue by a higher priority WorkflowRun"
+
+// Queue is a bounded priority queue of waiting WorkflowRuns. It's safe for
+// concurrent use.
+type Queue struct {
+	mu      sync.Mutex
+	heap    entryHeap
+	byKey   map[string]*Entry
+	maxSize int64
+	onEvict func(*Entry)
+}
+
+// NewQueue creates a waiting queue bounded to maxSize entries; a maxSize of 0
+// or less leaves it unbounded. onEvict, if
+// non-nil, is called (outside the queue lock) for every entry evicted to
+// make room for a higher priority arrival; callers use it to fail the
+// evicted WorkflowRun with EvictionReason.
+func NewQueue(maxSize int64, onEvict func(*Entry)) *Queue {
+	return &Queue{
+		byKey:   make(map[string]*Entry),
+		maxSize: maxSize,
+		onEvict: onEvict,
+	}
+}
+
+func key(namespace, name string) string { return namespace + "/" + name }
+
+// Push adds a WorkflowRun to the waiting queue. If the queue is at capacity,
+// the lowest-priority entry is evicted to make room, unless the new entry
+// itself is the lowest priority, in which case it is rejected (ok=false)
+// and the queue is left untouched.
+func (q *Queue) Push(e *Entry) (ok bool) {
+	q.mu.Lock()
+
+	if _, found := q.byKey[key(e.Namespace, e.WorkflowRun)]; found {
+		q.mu.Unlock()
+		return true
+	}
+
+	e.EnqueuedAt = now()
+
+	var evicted *Entry
+	if q.maxSize > 0 && int64(len(q.heap)) >= q.maxSize {
+		lowest := q.lowest()
+		if lowest == nil || higherPriority(lowest, e) {
+			// The new entry is not higher priority than the current lowest, reject it.
+			q.mu.Unlock()
+			return false
+		}
+		evicted = q.removeLocked(lowest)
+	}
+
+	heap.Push(&q.heap, e)
+	q.byKey[key(e.Namespace, e.WorkflowRun)] = e
+	q.mu.Unlock()
+
+	if evicted != nil {
+		if q.onEvict != nil {
+			q.onEvict(evicted)
+		}
+		observeEviction(priorityBand(evicted.Priority))
+	}
+	observeEnqueue()
+	observeQueueDepth(q.size())
+	return true
+}
+
+// Pop removes and returns the highest priority waiting entry, or nil if the
+// queue is empty.
+func (q *Queue) Pop() *Entry {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.heap) == 0 {
+		return nil
+	}
+	e := heap.Pop(&q.heap).(*Entry)
+	delete(q.byKey, key(e.Namespace, e.WorkflowRun))
+	observeWaitTime(now().Sub(e.EnqueuedAt))
+	observeQueueDepth(int64(len(q.heap)))
+	return e
+}
+
+// Remove removes an entry from the queue by namespace/name, e.g. when the
+// WorkflowRun is deleted while still waiting.
+func (q *Queue) Remove(namespace, name string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	e, found := q.byKey[key(namespace, name)]
+	if !found {
+		return
+	}
+	q.removeLocked(e)
+}
+
+func (q *Queue) removeLocked(e *Entry) *Entry {
+	heap.Remove(&q.heap, e.index)
+	delete(q.byKey, key(e.Namespace, e.WorkflowRun))
+	return e
+}
+
+func (q *Queue) lowest() *Entry {
+	var lowest *Entry
+	for _, e := range q.heap {
+		if lowest == nil || higherPriority(lowest, e) {
+			lowest = e
+		}
+	}
+	return lowest
+}
+
+func (q *Queue) size() int64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return int64(len(q.heap))
+}
+
+// now is a var so it can be stubbed in tests.
+var now = time.Now
+
+// priorityBand buckets a raw priority value into a small set of labels
+// suitable for metrics cardinality.
+func priorityBand(priority int32) string {
+	switch {
+	case priority >= 100:
+		return "high"
+	case priority <= 0:
+		return "low"
+	default:
+		return "normal"
+	}
+}
+
+// NamespaceConstraint resolves the effective ParallelismConstraint for a
+// namespace, falling back to the Overall constraint when the namespace has
+// no specific entry configured.
+func NamespaceConstraint(namespace string) controller.ParallelismConstraint {
+	cfg := controller.Get().Parallelism
+	if cfg == nil {
+		return controller.ParallelismConstraint{}
+	}
+	if c, ok := cfg.Namespace[namespace]; ok {
+		return c
+	}
+	return cfg.Overall
+}
@@ -0,0 +1,80 @@
+package admission
+
+import (
+	"testing"
+
+	"github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestQueuePopOrdersByPriorityThenAge(t *testing.T) {
+	q := NewQueue(0, nil)
+
+	q.Push(&Entry{Namespace: "ns", WorkflowRun: "low", Priority: 1})
+	q.Push(&Entry{Namespace: "ns", WorkflowRun: "high", Priority: 10})
+	q.Push(&Entry{Namespace: "ns", WorkflowRun: "also-low", Priority: 1})
+
+	order := []string{q.Pop().WorkflowRun, q.Pop().WorkflowRun, q.Pop().WorkflowRun}
+	if order[0] != "high" {
+		t.Fatalf("expected highest priority entry first, got %v", order)
+	}
+	if order[1] != "low" || order[2] != "also-low" {
+		t.Fatalf("expected same-priority entries in enqueue order, got %v", order)
+	}
+}
+
+func TestQueueEvictsLowerPriorityWhenFull(t *testing.T) {
+	var evicted *Entry
+	q := NewQueue(1, func(e *Entry) { evicted = e })
+
+	q.Push(&Entry{Namespace: "ns", WorkflowRun: "low", Priority: 1})
+	ok := q.Push(&Entry{Namespace: "ns", WorkflowRun: "high", Priority: 10})
+	if !ok {
+		t.Fatal("expected higher priority entry to be admitted")
+	}
+	if evicted == nil || evicted.WorkflowRun != "low" {
+		t.Fatalf("expected lower priority entry to be evicted, got %v", evicted)
+	}
+
+	remaining := q.Pop()
+	if remaining == nil || remaining.WorkflowRun != "high" {
+		t.Fatalf("expected surviving entry to be 'high', got %v", remaining)
+	}
+}
+
+func TestQueueRejectsWhenNewEntryIsLowestPriority(t *testing.T) {
+	q := NewQueue(1, func(e *Entry) { t.Fatalf("unexpected eviction of %v", e) })
+
+	q.Push(&Entry{Namespace: "ns", WorkflowRun: "high", Priority: 10})
+	ok := q.Push(&Entry{Namespace: "ns", WorkflowRun: "low", Priority: 1})
+	if ok {
+		t.Fatal("expected lower priority entry to be rejected when queue is full")
+	}
+}
+
+func TestQueueRejectsEqualPriorityArrivalWhenFull(t *testing.T) {
+	// Most WorkflowRuns don't set spec.priority and default to 0, so a
+	// saturated queue seeing same-priority arrivals is the common case. The
+	// existing waiter must not be evicted to make room for a new arrival of
+	// equal priority.
+	q := NewQueue(2, func(e *Entry) { t.Fatalf("unexpected eviction of %v", e) })
+
+	q.Push(&Entry{Namespace: "ns", WorkflowRun: "first"})
+	q.Push(&Entry{Namespace: "ns", WorkflowRun: "second"})
+	ok := q.Push(&Entry{Namespace: "ns", WorkflowRun: "third"})
+	if ok {
+		t.Fatal("expected equal priority arrival to be rejected when queue is full")
+	}
+}
+
+func TestEntryForWorkflowRunReadsSpecPriority(t *testing.T) {
+	wfr := &v1alpha1.WorkflowRun{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "run-1"},
+		Spec:       v1alpha1.WorkflowRunSpec{WorkflowRef: "wf-1", Priority: 5},
+	}
+
+	e := EntryForWorkflowRun(wfr)
+	if e.Namespace != "ns" || e.WorkflowRun != "run-1" || e.Workflow != "wf-1" || e.Priority != 5 {
+		t.Fatalf("unexpected entry: %+v", e)
+	}
+}
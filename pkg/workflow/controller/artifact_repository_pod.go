@@ -0,0 +1,103 @@
+package controller
+
+import (
+	"encoding/json"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
+)
+
+const (
+	// ArtifactRepositoryEnvName is the env var the coordinator and
+	// resource-resolver containers read the effective ArtifactRepository from,
+	// JSON-encoded.
+	ArtifactRepositoryEnvName = "ARTIFACT_REPOSITORY"
+
+	// CoordinatorContainerName is the name of the coordinator container in a
+	// stage Pod.
+	CoordinatorContainerName = "coordinator"
+	// ResolverContainerPrefix is the name prefix of resource-resolver
+	// containers in a stage Pod; each input/output resource gets its own
+	// resolver container named "resolver-<resource>".
+	ResolverContainerPrefix = "resolver-"
+)
+
+// ApplyArtifactRepository resolves the effective ArtifactRepository for wfr
+// (see ArtifactRepositoryForRun) and injects it into the coordinator and
+// resource-resolver containers of pod as ArtifactRepositoryEnvName, so those
+// containers can push/pull artifacts to/from the configured object store
+// instead of requiring ExecutionContext.PVC. It's a no-op if no
+// ArtifactRepository applies.
+//
+// Access credentials referenced by SecretKeySelector fields are wired as
+// container env vars sourced from the secret, not inlined into
+// ArtifactRepositoryEnvName, so they never end up in the Pod spec itself.
+func ApplyArtifactRepository(pod *corev1.Pod, wfr *v1alpha1.WorkflowRun, ec *ExecutionContext) error {
+	repo := ArtifactRepositoryForRun(wfr, ec)
+	if repo == nil {
+		return nil
+	}
+
+	encoded, err := json.Marshal(repo)
+	if err != nil {
+		return err
+	}
+
+	credentials := credentialEnvVars(repo)
+	inject := func(containers []corev1.Container) {
+		for i := range containers {
+			c := &containers[i]
+			if !isArtifactRepositoryConsumer(c.Name) {
+				continue
+			}
+			c.Env = append(c.Env, corev1.EnvVar{Name: ArtifactRepositoryEnvName, Value: string(encoded)})
+			c.Env = append(c.Env, credentials...)
+		}
+	}
+	// Resource resolvers may run as init containers as well as regular ones.
+	inject(pod.Spec.InitContainers)
+	inject(pod.Spec.Containers)
+
+	return nil
+}
+
+func isArtifactRepositoryConsumer(containerName string) bool {
+	return containerName == CoordinatorContainerName || strings.HasPrefix(containerName, ResolverContainerPrefix)
+}
+
+// credentialEnvVars turns the secret references in repo's configured backend
+// into env vars sourced from those secrets.
+func credentialEnvVars(repo *ArtifactRepository) []corev1.EnvVar {
+	switch {
+	case repo.S3 != nil:
+		return []corev1.EnvVar{
+			secretEnvVar("ARTIFACT_REPOSITORY_ACCESS_KEY", repo.S3.AccessKeySecretRef),
+			secretEnvVar("ARTIFACT_REPOSITORY_SECRET_KEY", repo.S3.SecretKeySecretRef),
+		}
+	case repo.OSS != nil:
+		return []corev1.EnvVar{
+			secretEnvVar("ARTIFACT_REPOSITORY_ACCESS_KEY", repo.OSS.AccessKeySecretRef),
+			secretEnvVar("ARTIFACT_REPOSITORY_SECRET_KEY", repo.OSS.SecretKeySecretRef),
+		}
+	case repo.GCS != nil:
+		return []corev1.EnvVar{
+			secretEnvVar("ARTIFACT_REPOSITORY_SERVICE_ACCOUNT_KEY", repo.GCS.ServiceAccountKeySecretRef),
+		}
+	default:
+		return nil
+	}
+}
+
+func secretEnvVar(name string, ref SecretKeySelector) corev1.EnvVar {
+	return corev1.EnvVar{
+		Name: name,
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: ref.Name},
+				Key:                  ref.Key,
+			},
+		},
+	}
+}
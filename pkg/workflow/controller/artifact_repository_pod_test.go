@@ -0,0 +1,76 @@
+package controller
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
+)
+
+func TestApplyArtifactRepositoryInjectsEnvIntoCoordinatorAndResolversOnly(t *testing.T) {
+	pod := &corev1.Pod{
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{Name: CoordinatorContainerName},
+				{Name: "resolver-input"},
+				{Name: "stage"},
+			},
+		},
+	}
+	wfr := &v1alpha1.WorkflowRun{
+		Spec: v1alpha1.WorkflowRunSpec{
+			ArtifactRepository: &v1alpha1.ArtifactRepository{
+				S3: &v1alpha1.S3ArtifactRepository{Bucket: "artifacts"},
+			},
+		},
+	}
+
+	if err := ApplyArtifactRepository(pod, wfr, nil); err != nil {
+		t.Fatalf("ApplyArtifactRepository returned error: %v", err)
+	}
+
+	for _, name := range []string{CoordinatorContainerName, "resolver-input"} {
+		c := containerByName(pod, name)
+		if !hasEnv(c, ArtifactRepositoryEnvName) {
+			t.Fatalf("expected %s to have %s set", name, ArtifactRepositoryEnvName)
+		}
+	}
+
+	if hasEnv(containerByName(pod, "stage"), ArtifactRepositoryEnvName) {
+		t.Fatal("expected stage container to be untouched")
+	}
+}
+
+func TestApplyArtifactRepositoryNoopWithoutRepository(t *testing.T) {
+	pod := &corev1.Pod{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: CoordinatorContainerName}}}}
+	wfr := &v1alpha1.WorkflowRun{}
+
+	if err := ApplyArtifactRepository(pod, wfr, nil); err != nil {
+		t.Fatalf("ApplyArtifactRepository returned error: %v", err)
+	}
+	if hasEnv(containerByName(pod, CoordinatorContainerName), ArtifactRepositoryEnvName) {
+		t.Fatal("expected no env to be set when no ArtifactRepository applies")
+	}
+}
+
+func containerByName(pod *corev1.Pod, name string) *corev1.Container {
+	for i := range pod.Spec.Containers {
+		if pod.Spec.Containers[i].Name == name {
+			return &pod.Spec.Containers[i]
+		}
+	}
+	return nil
+}
+
+func hasEnv(c *corev1.Container, name string) bool {
+	if c == nil {
+		return false
+	}
+	for _, e := range c.Env {
+		if e.Name == name {
+			return true
+		}
+	}
+	return false
+}
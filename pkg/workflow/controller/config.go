@@ -4,10 +4,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"sync"
 	"time"
 
 	log "github.com/sirupsen/logrus"
 	corev1 "k8s.io/api/core/v1"
+
+	"github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
 )
 
 const (
@@ -27,6 +30,13 @@ const (
 	DindImage = "dind"
 	// ToolboxImage is key of the cyclone toolbox image in config file
 	ToolboxImage = "toolbox"
+
+	// InstanceIDLabel is the label key a controller with
+	// WorkflowControllerConfig.InstanceID set selects Workflows/WorkflowRuns by
+	// (see InstanceIDSelector) and stamps on the Pods it creates for them (see
+	// StampInstanceID), so that multiple sharded controllers can coexist in one
+	// cluster without racing on the same objects.
+	InstanceIDLabel = "cyclone.io/controller-instanceid"
 )
 
 // WorkflowControllerConfig configures Workflow Controller
@@ -54,8 +64,66 @@ type WorkflowControllerConfig struct {
 	DindSettings DindSettings `json:"dind"`
 	// WorkersNumber defines workers number for various controller
 	WorkersNumber WorkersNumber `json:"workers_number"`
+	// TTLDefaults gives cluster-wide default TTL-after-finished values for
+	// WorkflowRuns that don't specify their own spec.ttlStrategy.
+	TTLDefaults TTLDefaults `json:"ttl_defaults"`
+	// Namespace restricts this controller to watch Workflows/WorkflowRuns in a
+	// single namespace. Empty means watch all namespaces.
+	Namespace string `json:"namespace"`
+	// InstanceID, when set, restricts this controller to watch only
+	// Workflows/WorkflowRuns carrying the InstanceIDLabel with this value, and
+	// causes it to stamp the same label on every stage Pod and GC Pod it
+	// creates. This allows multiple sharded Cyclone controllers, or a
+	// blue/green pair during an upgrade, to coexist in one cluster.
+	InstanceID string `json:"instance_id"`
+	// ArtifactRepository is the default backing object store used to transfer
+	// WorkflowRun artifacts between stages, for clusters that don't have a RWX
+	// PVC available. A WorkflowRun can override it via its own
+	// spec.artifactRepository.
+	ArtifactRepository *ArtifactRepository `json:"artifact_repository"`
+	// ExtraVolumes are merged into every stage Pod's volumes, e.g. to mount a
+	// shared CA bundle or docker config via hostPath/secret. ExecutionContext's
+	// own ExtraVolumes, if any, are appended after these.
+	ExtraVolumes []corev1.Volume `json:"extra_volumes"`
+	// ExtraVolumeMounts are merged into every container of every stage Pod.
+	ExtraVolumeMounts []corev1.VolumeMount `json:"extra_volume_mounts"`
+	// NodeSelector is merged into every stage Pod's node selector, e.g. to pin
+	// builds to a dedicated node pool.
+	NodeSelector map[string]string `json:"node_selector"`
+	// Tolerations are merged into every stage Pod's tolerations, e.g. to honor
+	// per-tenant taints.
+	Tolerations []corev1.Toleration `json:"tolerations"`
+	// Affinity, if set, replaces every stage Pod's affinity, including one the
+	// Pod already had; an ExecutionContext's own Affinity takes precedence.
+	Affinity *corev1.Affinity `json:"affinity"`
 }
 
+// ArtifactRepository describes a backing object store used to push/pull
+// WorkflowRun artifacts, as an alternative to the shared PVC
+// (ExecutionContext.PVC). Exactly one of the typed sub-configs should be set;
+// the coordinator and resource-resolver containers pick whichever one is
+// non-nil. It's a type alias for v1alpha1.ArtifactRepository so a
+// WorkflowRun's spec.artifactRepository override uses the exact same schema.
+type ArtifactRepository = v1alpha1.ArtifactRepository
+
+// SecretKeySelector names a key within a secret, analogous to corev1.SecretKeySelector.
+type SecretKeySelector = v1alpha1.SecretKeySelector
+
+// S3ArtifactRepository configures an S3-compatible object store.
+type S3ArtifactRepository = v1alpha1.S3ArtifactRepository
+
+// GCSArtifactRepository configures Google Cloud Storage.
+type GCSArtifactRepository = v1alpha1.GCSArtifactRepository
+
+// OSSArtifactRepository configures Alibaba Cloud OSS.
+type OSSArtifactRepository = v1alpha1.OSSArtifactRepository
+
+// HTTPArtifactRepository configures a plain HTTP(S) artifact server.
+type HTTPArtifactRepository = v1alpha1.HTTPArtifactRepository
+
+// HDFSArtifactRepository configures an HDFS cluster.
+type HDFSArtifactRepository = v1alpha1.HDFSArtifactRepository
+
 // LoggingConfig configures logging
 type LoggingConfig struct {
 	Level string `json:"level"`
@@ -68,10 +136,26 @@ type ExecutionContext struct {
 	// PVC is pvc used to run workflow. It's used to transfer artifacts in WorkflowRun, and
 	// also to help share resources among stages within WorkflowRun. If no PVC is given here,
 	// input resources won't be shared among stages, but need to be pulled every time it's needed.
-	// And also if no PVC given, artifacts are not supported.
+	// If neither PVC nor ArtifactRepository is given, artifacts are not supported.
 	PVC string `json:"pvc"`
 	// ServiceAccount is the service account applied to the pod runed
 	ServiceAccount string `json:"service_account"`
+	// ArtifactRepository overrides WorkflowControllerConfig.ArtifactRepository for
+	// WorkflowRuns using this ExecutionContext.
+	ArtifactRepository *ArtifactRepository `json:"artifact_repository"`
+	// ExtraVolumes are appended to WorkflowControllerConfig.ExtraVolumes for stage
+	// Pods using this ExecutionContext.
+	ExtraVolumes []corev1.Volume `json:"extra_volumes"`
+	// ExtraVolumeMounts are appended to WorkflowControllerConfig.ExtraVolumeMounts
+	// for every container of stage Pods using this ExecutionContext.
+	ExtraVolumeMounts []corev1.VolumeMount `json:"extra_volume_mounts"`
+	// NodeSelector is merged over WorkflowControllerConfig.NodeSelector, taking
+	// precedence on key conflicts.
+	NodeSelector map[string]string `json:"node_selector"`
+	// Tolerations are appended to WorkflowControllerConfig.Tolerations.
+	Tolerations []corev1.Toleration `json:"tolerations"`
+	// Affinity, if set, overrides WorkflowControllerConfig.Affinity.
+	Affinity *corev1.Affinity `json:"affinity"`
 }
 
 // GCConfig configures GC
@@ -91,6 +175,24 @@ type GCConfig struct {
 type LimitsConfig struct {
 	// Maximum WorkflowRuns to be kept for each Workflow
 	MaxWorkflowRuns int `json:"max_workflowruns"`
+	// RetentionPolicy, when set, overrides MaxWorkflowRuns with separate caps per
+	// terminate outcome, e.g. keeping more failed WorkflowRuns than succeeded ones.
+	RetentionPolicy *RetentionPolicy `json:"retention_policy"`
+}
+
+// RetentionPolicy caps how many terminated WorkflowRuns to retain for each Workflow,
+// broken down by outcome. Runs beyond a bucket's cap are deleted, oldest first, through
+// the same GC path as GCConfig.DelaySeconds.
+type RetentionPolicy struct {
+	// Succeeded is the max number of Succeeded WorkflowRuns to keep for each
+	// Workflow. Nil means unbounded; 0 means keep none.
+	Succeeded *int `json:"succeeded"`
+	// Failed is the max number of Failed WorkflowRuns to keep for each
+	// Workflow. Nil means unbounded; 0 means keep none.
+	Failed *int `json:"failed"`
+	// Errored is the max number of Cancelled/Errored WorkflowRuns to keep for
+	// each Workflow. Nil means unbounded; 0 means keep none.
+	Errored *int `json:"errored"`
 }
 
 // ParallelismConstraint puts constraints on parallelism
@@ -102,13 +204,18 @@ type ParallelismConstraint struct {
 }
 
 // ParallelismConfig configures how many WorkflowRun allows to run in parallel. If maximum parallelism exceeded,
-// new WorkflowRun will wait in waiting queue. Waiting queue will also have a maxinum size, if maxinum size exceeded,
-// new WorkflowRun will fail directly.
+// new WorkflowRun will wait in waiting queue, ordered by priority (see WorkflowRun spec.priority) rather than
+// arrival order. Waiting queue will also have a maxinum size, if maxinum size exceeded, the lowest-priority
+// waiting WorkflowRun is evicted (failed) to make room for the new one; only when the new one is itself the
+// lowest priority will it be rejected directly.
 type ParallelismConfig struct {
 	// Overall controls overall parallelism of WorkflowRun executions
 	Overall ParallelismConstraint `json:"overall"`
 	// SingleWorkflow controls parallelism of WorkflowRun executions for single Workflow
 	SingleWorkflow ParallelismConstraint `json:"single_workflow"`
+	// Namespace controls parallelism of WorkflowRun executions per namespace, keyed by namespace name.
+	// Namespaces not present here are only bound by Overall.
+	Namespace map[string]ParallelismConstraint `json:"namespace"`
 }
 
 // DindSettings is settings for Docker in Docker.
@@ -126,36 +233,92 @@ type WorkersNumber struct {
 	WorkflowTrigger  int `json:"workflow_trigger"`
 	WorkflowRun      int `json:"workflow_run"`
 	Pod              int `json:"pod"`
+	// TTLController is the number of workers processing TTL-after-finished
+	// deletion of terminated WorkflowRuns.
+	TTLController int `json:"ttl_controller"`
 }
 
-// Config is Workflow Controller config instance
-var Config WorkflowControllerConfig
+// TTLDefaults gives cluster-wide default TTL values applied to WorkflowRuns
+// that don't set spec.ttlStrategy themselves.
+type TTLDefaults struct {
+	// SecondsAfterCompletion is the default TTL applied regardless of outcome.
+	SecondsAfterCompletion *int64 `json:"seconds_after_completion"`
+	// SecondsAfterSuccess is the default TTL applied to Succeeded WorkflowRuns.
+	SecondsAfterSuccess *int64 `json:"seconds_after_success"`
+	// SecondsAfterFailure is the default TTL applied to Failed, Error and Cancelled WorkflowRuns.
+	SecondsAfterFailure *int64 `json:"seconds_after_failure"`
+}
+
+var (
+	// configMu guards config. Use Get to read it and swap to replace it;
+	// don't access config directly outside this file.
+	configMu sync.RWMutex
+	config   WorkflowControllerConfig
+)
 
-// LoadConfig loads configuration from ConfigMap
+// Get returns a snapshot of the current Workflow Controller configuration.
+// It's safe to call concurrently with a config reload triggered by WatchConfig.
+func Get() WorkflowControllerConfig {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	return config
+}
+
+// LoadConfig loads configuration from ConfigMap. It's used for the initial
+// load at startup; use WatchConfig to additionally keep the config
+// up to date as the backing ConfigMap changes.
 func LoadConfig(cm *corev1.ConfigMap) error {
+	parsed, err := parseConfig(cm)
+	if err != nil {
+		return err
+	}
+
+	swap(parsed)
+	return nil
+}
+
+// parseConfig decodes, validates and defaults a WorkflowControllerConfig from
+// a ConfigMap, without touching the live config.
+func parseConfig(cm *corev1.ConfigMap) (*WorkflowControllerConfig, error) {
 	data, ok := cm.Data[ConfigFileKey]
 	if !ok {
-		return fmt.Errorf("ConfigMap '%s' doesn't have data key '%s'", cm.Name, ConfigFileKey)
+		return nil, fmt.Errorf("ConfigMap '%s' doesn't have data key '%s'", cm.Name, ConfigFileKey)
 	}
-	err := json.Unmarshal([]byte(data), &Config)
-	if err != nil {
+
+	parsed := WorkflowControllerConfig{}
+	if err := json.Unmarshal([]byte(data), &parsed); err != nil {
 		log.WithField("data", data).Debug("Unmarshal config data error: ", err)
-		return err
+		return nil, err
 	}
 
-	if !validate(&Config) {
-		return fmt.Errorf("validate config failed")
+	if !validate(&parsed) {
+		return nil, fmt.Errorf("validate config failed")
 	}
 
-	defaultValues(&Config)
-	InitLogger(&Config.Logging)
-	return nil
+	defaultValues(&parsed)
+	return &parsed, nil
+}
+
+// swap installs parsed as the live config under configMu, and takes the
+// live-reload actions for fields that support it.
+func swap(parsed *WorkflowControllerConfig) {
+	configMu.Lock()
+	previous := config
+	config = *parsed
+	configMu.Unlock()
+
+	InitLogger(&parsed.Logging)
+	applyLiveReload(&previous, parsed)
 }
 
 // validate validates some required configurations.
 func validate(config *WorkflowControllerConfig) bool {
 	if config.ExecutionContext.PVC == "" {
-		log.Warn("PVC not configured, resources won't be shared among stages and artifacts unsupported.")
+		if config.ExecutionContext.ArtifactRepository == nil && config.ArtifactRepository == nil {
+			log.Warn("PVC not configured, resources won't be shared among stages and artifacts unsupported unless ArtifactRepository is configured.")
+		} else {
+			log.Warn("PVC not configured, resources won't be shared among stages.")
+		}
 	}
 
 	return true
@@ -179,6 +342,58 @@ func defaultValues(config *WorkflowControllerConfig) {
 		config.WorkersNumber.WorkflowRun = 1
 		log.Info("WorkersNumber.WorkflowRun not configured, will use default value '1'")
 	}
+	if config.WorkersNumber.TTLController == 0 {
+		config.WorkersNumber.TTLController = 1
+		log.Info("WorkersNumber.TTLController not configured, will use default value '1'")
+	}
+}
+
+// EffectiveArtifactRepository resolves the ArtifactRepository that applies to
+// a WorkflowRun, in order of precedence: the WorkflowRun's own
+// spec.artifactRepository (runOverride, nil if it doesn't set one), then its
+// ExecutionContext's override, then the cluster-wide default in
+// WorkflowControllerConfig.
+func EffectiveArtifactRepository(runOverride *ArtifactRepository, ec *ExecutionContext) *ArtifactRepository {
+	if runOverride != nil {
+		return runOverride
+	}
+	if ec != nil && ec.ArtifactRepository != nil {
+		return ec.ArtifactRepository
+	}
+	return Get().ArtifactRepository
+}
+
+// ArtifactRepositoryForRun resolves the ArtifactRepository that applies to
+// wfr, reading its spec.artifactRepository as the runOverride for
+// EffectiveArtifactRepository.
+func ArtifactRepositoryForRun(wfr *v1alpha1.WorkflowRun, ec *ExecutionContext) *ArtifactRepository {
+	return EffectiveArtifactRepository(wfr.Spec.ArtifactRepository, ec)
+}
+
+// InstanceIDSelector returns the label selector string used by informers/listers
+// to restrict watched Workflows/WorkflowRuns to this controller's InstanceID.
+// It returns an empty string (matching everything) when InstanceID isn't set.
+func InstanceIDSelector() string {
+	instanceID := Get().InstanceID
+	if instanceID == "" {
+		return ""
+	}
+	return InstanceIDLabel + "=" + instanceID
+}
+
+// StampInstanceID adds the InstanceIDLabel to labels when this controller is
+// configured with an InstanceID, so Pods it creates can be traced back to it
+// and won't be picked up by other sharded controllers. labels may be nil.
+func StampInstanceID(labels map[string]string) map[string]string {
+	instanceID := Get().InstanceID
+	if instanceID == "" {
+		return labels
+	}
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	labels[InstanceIDLabel] = instanceID
+	return labels
 }
 
 // ImagePullPolicy determines image pull policy based on environment variable DEVELOP_MODE
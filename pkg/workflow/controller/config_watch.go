@@ -0,0 +1,103 @@
+package controller
+
+import (
+	"reflect"
+
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+)
+
+// reloadableWorkerPool is a worker pool that can be resized live in reaction
+// to a config reload. A controller registers itself here (by WorkersNumber
+// field name) during startup so WatchConfig can resize it instead of
+// requiring a restart.
+type reloadableWorkerPool interface {
+	// Resize changes the number of running workers to n, starting or
+	// stopping goroutines as needed.
+	Resize(n int)
+}
+
+var workerPools = map[string]reloadableWorkerPool{}
+
+// RegisterWorkerPool lets a controller opt into live resizing of its worker
+// count when WorkersNumber changes in a reloaded config. name must match one
+// of the WorkersNumber JSON field names, e.g. "workflow_run".
+func RegisterWorkerPool(name string, pool reloadableWorkerPool) {
+	workerPools[name] = pool
+}
+
+// WatchConfig watches the given ConfigMap and reloads the config returned by Get whenever it
+// changes, until stopCh is closed. Unlike the one-shot LoadConfig, this
+// keeps the controller's live configuration in sync with the ConfigMap
+// without requiring a pod restart:
+//
+//   - logging level, GC delay/retry, parallelism limits, workers numbers and
+//     default resource requirements take effect immediately;
+//   - image names only log a warning, since Pods already running keep using
+//     the image they were created with;
+//   - a malformed edit is rejected (an Event is recorded and the previous
+//     config keeps running) rather than crashing the controller.
+func WatchConfig(client kubernetes.Interface, recorder record.EventRecorder, namespace, name string, stopCh <-chan struct{}) {
+	selector := fields.OneTermEqualSelector("metadata.name", name)
+	lw := cache.NewListWatchFromClient(client.CoreV1().RESTClient(), "configmaps", namespace, selector)
+
+	_, controller := cache.NewInformer(lw, &corev1.ConfigMap{}, 0, cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			reload(obj.(*corev1.ConfigMap), recorder)
+		},
+		UpdateFunc: func(_, obj interface{}) {
+			reload(obj.(*corev1.ConfigMap), recorder)
+		},
+	})
+
+	controller.Run(stopCh)
+}
+
+func reload(cm *corev1.ConfigMap, recorder record.EventRecorder) {
+	parsed, err := parseConfig(cm)
+	if err != nil {
+		log.WithError(err).Error("Reload workflow controller config failed, keeping previous config")
+		if recorder != nil {
+			recorder.Eventf(cm, corev1.EventTypeWarning, "ConfigReloadFailed", "Failed to reload %s: %v", ConfigFileKey, err)
+		}
+		return
+	}
+
+	swap(parsed)
+
+	log.Info("Reloaded workflow controller config")
+	if recorder != nil {
+		recorder.Eventf(cm, corev1.EventTypeNormal, "ConfigReloaded", "Reloaded %s", ConfigFileKey)
+	}
+}
+
+// applyLiveReload reacts to the diff between the previous and newly swapped
+// in config, resizing worker pools and warning about fields that can't take
+// effect without restarting already-running Pods.
+func applyLiveReload(previous, current *WorkflowControllerConfig) {
+	resizeWorkerPool("execution_cluster", previous.WorkersNumber.ExecutionCluster, current.WorkersNumber.ExecutionCluster)
+	resizeWorkerPool("workflow_trigger", previous.WorkersNumber.WorkflowTrigger, current.WorkersNumber.WorkflowTrigger)
+	resizeWorkerPool("workflow_run", previous.WorkersNumber.WorkflowRun, current.WorkersNumber.WorkflowRun)
+	resizeWorkerPool("pod", previous.WorkersNumber.Pod, current.WorkersNumber.Pod)
+	resizeWorkerPool("ttl_controller", previous.WorkersNumber.TTLController, current.WorkersNumber.TTLController)
+
+	if !reflect.DeepEqual(previous.Images, current.Images) {
+		log.Warn("Images changed in reloaded config; already-running stage Pods are unaffected, only new Pods use the new images")
+	}
+}
+
+func resizeWorkerPool(field string, previous, current int) {
+	if previous == current {
+		return
+	}
+	pool, ok := workerPools[field]
+	if !ok {
+		return
+	}
+	log.WithField("field", field).WithField("from", previous).WithField("to", current).Info("Resizing worker pool for reloaded config")
+	pool.Resize(current)
+}
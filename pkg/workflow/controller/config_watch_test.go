@@ -0,0 +1,61 @@
+package controller
+
+import "testing"
+
+type fakeWorkerPool struct {
+	resizedTo []int
+}
+
+func (p *fakeWorkerPool) Resize(n int) { p.resizedTo = append(p.resizedTo, n) }
+
+func TestResizeWorkerPoolSkipsUnchangedCount(t *testing.T) {
+	pool := &fakeWorkerPool{}
+	workerPools["test_unchanged"] = pool
+	defer delete(workerPools, "test_unchanged")
+
+	resizeWorkerPool("test_unchanged", 3, 3)
+
+	if len(pool.resizedTo) != 0 {
+		t.Fatalf("expected no resize when worker count is unchanged, got %v", pool.resizedTo)
+	}
+}
+
+func TestResizeWorkerPoolResizesOnChange(t *testing.T) {
+	pool := &fakeWorkerPool{}
+	workerPools["test_changed"] = pool
+	defer delete(workerPools, "test_changed")
+
+	resizeWorkerPool("test_changed", 3, 5)
+
+	if len(pool.resizedTo) != 1 || pool.resizedTo[0] != 5 {
+		t.Fatalf("expected a single resize to 5, got %v", pool.resizedTo)
+	}
+}
+
+func TestResizeWorkerPoolNoopWhenNotRegistered(t *testing.T) {
+	delete(workerPools, "test_unregistered")
+
+	// Must not panic even though nothing is registered under this name.
+	resizeWorkerPool("test_unregistered", 1, 2)
+}
+
+func TestApplyLiveReloadResizesOnlyChangedPools(t *testing.T) {
+	runPool := &fakeWorkerPool{}
+	podPool := &fakeWorkerPool{}
+	workerPools["workflow_run"] = runPool
+	workerPools["pod"] = podPool
+	defer delete(workerPools, "workflow_run")
+	defer delete(workerPools, "pod")
+
+	previous := &WorkflowControllerConfig{WorkersNumber: WorkersNumber{WorkflowRun: 2, Pod: 4}}
+	current := &WorkflowControllerConfig{WorkersNumber: WorkersNumber{WorkflowRun: 6, Pod: 4}}
+
+	applyLiveReload(previous, current)
+
+	if len(runPool.resizedTo) != 1 || runPool.resizedTo[0] != 6 {
+		t.Fatalf("expected workflow_run pool resized to 6, got %v", runPool.resizedTo)
+	}
+	if len(podPool.resizedTo) != 0 {
+		t.Fatalf("expected pod pool untouched since its worker count didn't change, got %v", podPool.resizedTo)
+	}
+}
@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"sort"
+
+	"github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
+)
+
+// retentionBucket classifies a terminated WorkflowRun into one of the
+// RetentionPolicy buckets based on its overall phase.
+func retentionBucket(wfr *v1alpha1.WorkflowRun) string {
+	switch wfr.Status.Overall.Phase {
+	case v1alpha1.StatusSucceeded:
+		return "succeeded"
+	case v1alpha1.StatusFailed:
+		return "failed"
+	default:
+		// Cancelled and Error phases share the same bucket.
+		return "errored"
+	}
+}
+
+// allBucket is the single pool terminated runs are grouped into when no
+// RetentionPolicy is configured, preserving MaxWorkflowRuns' original
+// semantics of one cap across all outcomes.
+const allBucket = "all"
+
+// capOf returns the configured cap for the given bucket, or nil if that
+// bucket is unbounded. Unlike a plain int, this distinguishes "not
+// configured" (nil, keep everything) from "configured to zero" (keep
+// nothing) for RetentionPolicy's pointer fields.
+func (l *LimitsConfig) capOf(bucket string) *int {
+	switch bucket {
+	case "succeeded":
+		return l.RetentionPolicy.Succeeded
+	case "failed":
+		return l.RetentionPolicy.Failed
+	case "errored":
+		return l.RetentionPolicy.Errored
+	default:
+		if l.MaxWorkflowRuns <= 0 {
+			return nil
+		}
+		max := l.MaxWorkflowRuns
+		return &max
+	}
+}
+
+// runsToReclaim returns the WorkflowRuns that exceed the retention cap. Within
+// each bucket they are ordered oldest (by finish time) first; the order of the
+// buckets relative to each other is not defined. Callers are expected to hand the result to
+// the existing GC path so deletion still honors GCConfig.DelaySeconds.
+//
+// When RetentionPolicy is configured, terminated runs are bucketed per
+// outcome and each bucket is capped independently. Otherwise, all terminated
+// runs for the Workflow share a single pool capped at MaxWorkflowRuns, as
+// before RetentionPolicy existed.
+func runsToReclaim(limits *LimitsConfig, runs []*v1alpha1.WorkflowRun) []*v1alpha1.WorkflowRun {
+	buckets := make(map[string][]*v1alpha1.WorkflowRun)
+	for _, r := range runs {
+		if !r.Status.Overall.Phase.IsTerminated() {
+			continue
+		}
+
+		bucket := allBucket
+		if limits.RetentionPolicy != nil {
+			bucket = retentionBucket(r)
+		}
+		buckets[bucket] = append(buckets[bucket], r)
+	}
+
+	var reclaim []*v1alpha1.WorkflowRun
+	for bucket, bucketRuns := range buckets {
+		capPtr := limits.capOf(bucket)
+		if capPtr == nil {
+			// Not configured: unbounded, keep everything.
+			continue
+		}
+
+		cap := *capPtr
+		if cap < 0 {
+			cap = 0
+		}
+		if len(bucketRuns) <= cap {
+			continue
+		}
+
+		sort.Slice(bucketRuns, func(i, j int) bool {
+			return bucketRuns[i].Status.Overall.LastTransitionTime.Before(&bucketRuns[j].Status.Overall.LastTransitionTime)
+		})
+
+		reclaim = append(reclaim, bucketRuns[:len(bucketRuns)-cap]...)
+	}
+
+	return reclaim
+}
@@ -0,0 +1,133 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
+)
+
+func run(name string, phase v1alpha1.Phase, finishedAt time.Time) *v1alpha1.WorkflowRun {
+	return &v1alpha1.WorkflowRun{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status: v1alpha1.WorkflowRunStatus{
+			Overall: v1alpha1.Status{
+				Phase:              phase,
+				LastTransitionTime: metav1.NewTime(finishedAt),
+			},
+		},
+	}
+}
+
+func intPtr(v int) *int { return &v }
+
+func TestRunsToReclaimSinglePoolWhenRetentionPolicyUnset(t *testing.T) {
+	now := time.Unix(1000, 0)
+	runs := []*v1alpha1.WorkflowRun{
+		run("s1", v1alpha1.StatusSucceeded, now),
+		run("s2", v1alpha1.StatusSucceeded, now.Add(time.Minute)),
+		run("f1", v1alpha1.StatusFailed, now.Add(2*time.Minute)),
+	}
+
+	limits := &LimitsConfig{MaxWorkflowRuns: 2}
+	reclaim := runsToReclaim(limits, runs)
+
+	if len(reclaim) != 1 || reclaim[0].Name != "s1" {
+		t.Fatalf("expected only the oldest run across the single pool to be reclaimed, got %v", names(reclaim))
+	}
+}
+
+func TestRunsToReclaimUnboundedWhenMaxWorkflowRunsUnset(t *testing.T) {
+	now := time.Unix(1000, 0)
+	runs := []*v1alpha1.WorkflowRun{
+		run("s1", v1alpha1.StatusSucceeded, now),
+		run("s2", v1alpha1.StatusSucceeded, now.Add(time.Minute)),
+	}
+
+	limits := &LimitsConfig{}
+	reclaim := runsToReclaim(limits, runs)
+
+	if len(reclaim) != 0 {
+		t.Fatalf("expected no reclaim when MaxWorkflowRuns is unset, got %v", names(reclaim))
+	}
+}
+
+func TestRunsToReclaimPerBucketWithRetentionPolicy(t *testing.T) {
+	now := time.Unix(1000, 0)
+	runs := []*v1alpha1.WorkflowRun{
+		run("s1", v1alpha1.StatusSucceeded, now),
+		run("s2", v1alpha1.StatusSucceeded, now.Add(time.Minute)),
+		run("f1", v1alpha1.StatusFailed, now),
+		run("f2", v1alpha1.StatusFailed, now.Add(time.Minute)),
+		run("f3", v1alpha1.StatusFailed, now.Add(2*time.Minute)),
+	}
+
+	limits := &LimitsConfig{
+		MaxWorkflowRuns: 100,
+		RetentionPolicy: &RetentionPolicy{
+			Succeeded: intPtr(1),
+			Failed:    intPtr(2),
+		},
+	}
+	reclaim := runsToReclaim(limits, runs)
+
+	if len(reclaim) != 2 {
+		t.Fatalf("expected 1 succeeded + 1 failed reclaimed independently, got %v", names(reclaim))
+	}
+	got := map[string]bool{}
+	for _, r := range reclaim {
+		got[r.Name] = true
+	}
+	if !got["s1"] || !got["f1"] {
+		t.Fatalf("expected oldest of each bucket reclaimed, got %v", names(reclaim))
+	}
+}
+
+func TestRunsToReclaimExplicitZeroCapReclaimsWholeBucket(t *testing.T) {
+	now := time.Unix(1000, 0)
+	runs := []*v1alpha1.WorkflowRun{
+		run("s1", v1alpha1.StatusSucceeded, now),
+		run("s2", v1alpha1.StatusSucceeded, now.Add(time.Minute)),
+	}
+
+	limits := &LimitsConfig{
+		RetentionPolicy: &RetentionPolicy{
+			Succeeded: intPtr(0),
+		},
+	}
+	reclaim := runsToReclaim(limits, runs)
+
+	if len(reclaim) != 2 {
+		t.Fatalf("expected explicit zero cap to reclaim every succeeded run, got %v", names(reclaim))
+	}
+}
+
+func TestRunsToReclaimUnsetBucketIsUnbounded(t *testing.T) {
+	now := time.Unix(1000, 0)
+	runs := []*v1alpha1.WorkflowRun{
+		run("f1", v1alpha1.StatusFailed, now),
+		run("f2", v1alpha1.StatusFailed, now.Add(time.Minute)),
+	}
+
+	limits := &LimitsConfig{
+		RetentionPolicy: &RetentionPolicy{
+			Succeeded: intPtr(0),
+			// Failed left nil: unbounded.
+		},
+	}
+	reclaim := runsToReclaim(limits, runs)
+
+	if len(reclaim) != 0 {
+		t.Fatalf("expected nil Failed cap to keep all failed runs, got %v", names(reclaim))
+	}
+}
+
+func names(runs []*v1alpha1.WorkflowRun) []string {
+	out := make([]string, len(runs))
+	for i, r := range runs {
+		out[i] = r.Name
+	}
+	return out
+}
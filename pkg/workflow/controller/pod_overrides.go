@@ -0,0 +1,78 @@
+package controller
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ApplyPodOverrides merges the cluster-wide Pod overrides from
+// WorkflowControllerConfig, and then the per-ExecutionContext overrides (ec
+// may be nil), into pod. Volumes, volume mounts and tolerations are additive
+// across both levels; NodeSelector is merged as a map with ExecutionContext
+// keys winning on conflict; Affinity is replaced wholesale, ExecutionContext
+// taking precedence over the config-level default when both are set. Inputs
+// are deep-copied so the shared config returned by Get is never mutated.
+func ApplyPodOverrides(pod *corev1.Pod, ec *ExecutionContext) {
+	cfg := Get()
+
+	mergeVolumes(pod, cfg.ExtraVolumes)
+	mergeVolumeMounts(pod, cfg.ExtraVolumeMounts)
+	mergeNodeSelector(pod, cfg.NodeSelector)
+	mergeTolerations(pod, cfg.Tolerations)
+	applyAffinity(pod, cfg.Affinity)
+
+	if ec == nil {
+		return
+	}
+	mergeVolumes(pod, ec.ExtraVolumes)
+	mergeVolumeMounts(pod, ec.ExtraVolumeMounts)
+	mergeNodeSelector(pod, ec.NodeSelector)
+	mergeTolerations(pod, ec.Tolerations)
+	applyAffinity(pod, ec.Affinity)
+}
+
+func mergeVolumes(pod *corev1.Pod, extra []corev1.Volume) {
+	for _, v := range extra {
+		pod.Spec.Volumes = append(pod.Spec.Volumes, *v.DeepCopy())
+	}
+}
+
+func mergeVolumeMounts(pod *corev1.Pod, extra []corev1.VolumeMount) {
+	if len(extra) == 0 {
+		return
+	}
+	for i := range pod.Spec.Containers {
+		for _, m := range extra {
+			pod.Spec.Containers[i].VolumeMounts = append(pod.Spec.Containers[i].VolumeMounts, *m.DeepCopy())
+		}
+	}
+	for i := range pod.Spec.InitContainers {
+		for _, m := range extra {
+			pod.Spec.InitContainers[i].VolumeMounts = append(pod.Spec.InitContainers[i].VolumeMounts, *m.DeepCopy())
+		}
+	}
+}
+
+func mergeNodeSelector(pod *corev1.Pod, extra map[string]string) {
+	if len(extra) == 0 {
+		return
+	}
+	if pod.Spec.NodeSelector == nil {
+		pod.Spec.NodeSelector = make(map[string]string, len(extra))
+	}
+	for k, v := range extra {
+		pod.Spec.NodeSelector[k] = v
+	}
+}
+
+func mergeTolerations(pod *corev1.Pod, extra []corev1.Toleration) {
+	for _, t := range extra {
+		pod.Spec.Tolerations = append(pod.Spec.Tolerations, *t.DeepCopy())
+	}
+}
+
+func applyAffinity(pod *corev1.Pod, affinity *corev1.Affinity) {
+	if affinity == nil {
+		return
+	}
+	pod.Spec.Affinity = affinity.DeepCopy()
+}
@@ -0,0 +1,66 @@
+package controller
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func newPodWithContainers() *corev1.Pod {
+	return &corev1.Pod{
+		Spec: corev1.PodSpec{
+			InitContainers: []corev1.Container{{Name: "resolver-input"}},
+			Containers:     []corev1.Container{{Name: "stage"}},
+		},
+	}
+}
+
+func TestMergeVolumeMountsAppliesToContainersAndInitContainers(t *testing.T) {
+	pod := newPodWithContainers()
+	mergeVolumeMounts(pod, []corev1.VolumeMount{{Name: "ca-bundle", MountPath: "/etc/ca"}})
+
+	if len(pod.Spec.Containers[0].VolumeMounts) != 1 {
+		t.Fatalf("expected container to get the extra volume mount, got %+v", pod.Spec.Containers[0].VolumeMounts)
+	}
+	if len(pod.Spec.InitContainers[0].VolumeMounts) != 1 {
+		t.Fatalf("expected init container (e.g. resource-resolver) to get the extra volume mount, got %+v", pod.Spec.InitContainers[0].VolumeMounts)
+	}
+}
+
+func TestMergeNodeSelectorExecutionContextWinsOnConflict(t *testing.T) {
+	pod := &corev1.Pod{}
+	mergeNodeSelector(pod, map[string]string{"pool": "config-level", "az": "us-east"})
+	mergeNodeSelector(pod, map[string]string{"pool": "ec-level"})
+
+	if pod.Spec.NodeSelector["pool"] != "ec-level" {
+		t.Fatalf("expected ExecutionContext-level value to win on conflict, got %q", pod.Spec.NodeSelector["pool"])
+	}
+	if pod.Spec.NodeSelector["az"] != "us-east" {
+		t.Fatalf("expected config-level keys without conflict to survive, got %q", pod.Spec.NodeSelector["az"])
+	}
+}
+
+func TestApplyAffinityExecutionContextOverridesConfig(t *testing.T) {
+	pod := &corev1.Pod{}
+	configAffinity := &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{}}
+	ecAffinity := &corev1.Affinity{PodAffinity: &corev1.PodAffinity{}}
+
+	applyAffinity(pod, configAffinity)
+	applyAffinity(pod, ecAffinity)
+
+	if pod.Spec.Affinity.PodAffinity == nil || pod.Spec.Affinity.NodeAffinity != nil {
+		t.Fatalf("expected ExecutionContext affinity to replace config-level affinity, got %+v", pod.Spec.Affinity)
+	}
+}
+
+func TestApplyAffinityDoesNotMutateSharedInput(t *testing.T) {
+	pod := &corev1.Pod{}
+	shared := &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{}}
+
+	applyAffinity(pod, shared)
+	pod.Spec.Affinity.NodeAffinity = nil
+
+	if shared.NodeAffinity == nil {
+		t.Fatal("expected applyAffinity to deep-copy, mutating the pod's affinity must not affect the shared input")
+	}
+}
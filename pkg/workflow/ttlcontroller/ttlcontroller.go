@@ -0,0 +1,160 @@
+// Package ttlcontroller implements a controller that deletes terminated
+// WorkflowRuns once their TTL-after-finished has elapsed. It complements
+// the GC controller (which only cleans up pods/PVCs left behind by a
+// WorkflowRun) by managing the lifetime of the WorkflowRun object itself.
+package ttlcontroller
+
+import (
+	"time"
+
+	"k8s.io/client-go/util/workqueue"
+
+	"github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
+	"github.com/caicloud/cyclone/pkg/k8s/clientset"
+	"github.com/caicloud/cyclone/pkg/workflow/controller"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Controller watches terminated WorkflowRuns and deletes them once their
+// spec.ttlStrategy (or the cluster-wide TTLDefaults) has elapsed. Pending
+// deletions are tracked in a delaying workqueue keyed by WorkflowRun
+// namespace/name, so many long-lived runs can be watched cheaply without
+// a goroutine or timer per run.
+type Controller struct {
+	client clientset.Interface
+	lister Lister
+
+	queue workqueue.DelayingInterface
+}
+
+// Lister looks up WorkflowRuns by namespace/name, backed by an indexer over
+// completed runs so the controller never needs a full scan to find work.
+type Lister interface {
+	GetCompletedWorkflowRun(namespace, name string) (*v1alpha1.WorkflowRun, error)
+}
+
+// NewController creates a new TTL controller.
+func NewController(client clientset.Interface, lister Lister) *Controller {
+	return &Controller{
+		client: client,
+		lister: lister,
+		queue:  workqueue.NewDelayingQueue(),
+	}
+}
+
+// Run starts workers processing the TTL queue until stopCh is closed.
+func (c *Controller) Run(workers int, stopCh <-chan struct{}) {
+	defer c.queue.ShutDown()
+
+	log.WithField("workers", workers).Info("Starting TTL controller")
+	for i := 0; i < workers; i++ {
+		go c.worker(stopCh)
+	}
+
+	<-stopCh
+	log.Info("Shutting down TTL controller")
+}
+
+// Enqueue schedules a terminated WorkflowRun for deletion once its TTL
+// expires. It's safe to call repeatedly: if the key is already waiting with
+// an earlier expiry, that earlier one stands, and process re-checks the
+// expiry when the key comes due and pushes it back if it has moved later.
+func (c *Controller) Enqueue(wfr *v1alpha1.WorkflowRun) {
+	expiry, ok := expiresAfter(wfr)
+	if !ok {
+		return
+	}
+
+	key := wfr.Namespace + "/" + wfr.Name
+	c.queue.AddAfter(key, time.Until(expiry))
+}
+
+func (c *Controller) worker(stopCh <-chan struct{}) {
+	for {
+		key, quit := c.queue.Get()
+		if quit {
+			return
+		}
+
+		c.process(key.(string))
+		c.queue.Done(key)
+	}
+}
+
+func (c *Controller) process(key string) {
+	namespace, name := splitKey(key)
+
+	wfr, err := c.lister.GetCompletedWorkflowRun(namespace, name)
+	if err != nil {
+		log.WithField("key", key).WithError(err).Warn("Get WorkflowRun for TTL deletion failed, skip")
+		return
+	}
+	if wfr == nil {
+		// Already gone.
+		return
+	}
+
+	expiry, ok := expiresAfter(wfr)
+	if !ok {
+		return
+	}
+	if remaining := time.Until(expiry); remaining > 0 {
+		// Status changed since it was enqueued, push back.
+		c.queue.AddAfter(key, remaining)
+		return
+	}
+
+	if err := c.client.CycloneV1alpha1().WorkflowRuns(namespace).Delete(name, nil); err != nil {
+		log.WithField("wfr", key).WithError(err).Error("Delete expired WorkflowRun failed")
+		return
+	}
+	log.WithField("wfr", key).Info("Deleted expired WorkflowRun")
+}
+
+// expiresAfter resolves the effective TTL for a terminated WorkflowRun from
+// its own spec.ttlStrategy, falling back to the cluster TTLDefaults, and
+// returns the absolute time it expires at. ok is false if the WorkflowRun
+// hasn't terminated yet or has no TTL configured.
+func expiresAfter(wfr *v1alpha1.WorkflowRun) (time.Time, bool) {
+	if !wfr.Status.Overall.Phase.IsTerminated() {
+		return time.Time{}, false
+	}
+
+	seconds := ttlSeconds(wfr)
+	if seconds == nil {
+		return time.Time{}, false
+	}
+
+	finishedAt := wfr.Status.Overall.LastTransitionTime
+	return finishedAt.Add(time.Duration(*seconds) * time.Second), true
+}
+
+func ttlSeconds(wfr *v1alpha1.WorkflowRun) *int64 {
+	strategy := wfr.Spec.TTLStrategy
+	defaults := controller.Get().TTLDefaults
+
+	switch {
+	case strategy != nil && strategy.SecondsAfterCompletion != nil:
+		return strategy.SecondsAfterCompletion
+	case strategy != nil && wfr.Status.Overall.Phase == v1alpha1.StatusSucceeded && strategy.SecondsAfterSuccess != nil:
+		return strategy.SecondsAfterSuccess
+	case strategy != nil && wfr.Status.Overall.Phase != v1alpha1.StatusSucceeded && strategy.SecondsAfterFailure != nil:
+		return strategy.SecondsAfterFailure
+	case defaults.SecondsAfterCompletion != nil:
+		return defaults.SecondsAfterCompletion
+	case wfr.Status.Overall.Phase == v1alpha1.StatusSucceeded:
+		return defaults.SecondsAfterSuccess
+	default:
+		return defaults.SecondsAfterFailure
+	}
+}
+
+func splitKey(key string) (namespace, name string) {
+	for i := 0; i < len(key); i++ {
+		if key[i] == '/' {
+			return key[:i], key[i+1:]
+		}
+	}
+	return "", key
+}
@@ -0,0 +1,171 @@
+package ttlcontroller
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/caicloud/cyclone/pkg/apis/cyclone/v1alpha1"
+	"github.com/caicloud/cyclone/pkg/workflow/controller"
+)
+
+func int64Ptr(v int64) *int64 { return &v }
+
+// loadTTLDefaults installs cluster-wide TTLDefaults via controller.LoadConfig,
+// the only seam the controller package exposes for changing the live config.
+func loadTTLDefaults(t *testing.T, defaults controller.TTLDefaults) {
+	t.Helper()
+
+	cfg := controller.WorkflowControllerConfig{TTLDefaults: defaults}
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		t.Fatalf("marshal config: %v", err)
+	}
+
+	cm := &corev1.ConfigMap{Data: map[string]string{controller.ConfigFileKey: string(data)}}
+	if err := controller.LoadConfig(cm); err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+}
+
+func TestEnqueueAddsTerminatedRunToQueue(t *testing.T) {
+	// Also a compile-level regression test: Enqueue must build a
+	// time.Duration for workqueue.DelayingInterface.AddAfter, not a time.Time.
+	// The run finished an hour ago, so its 60s TTL has already elapsed and
+	// AddAfter puts the key straight into the ready queue that Len counts.
+	c := NewController(nil, nil)
+
+	wfr := &v1alpha1.WorkflowRun{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "run-1"},
+		Spec:       v1alpha1.WorkflowRunSpec{TTLStrategy: &v1alpha1.TTLStrategy{SecondsAfterCompletion: int64Ptr(60)}},
+		Status:     v1alpha1.WorkflowRunStatus{Overall: v1alpha1.Status{Phase: v1alpha1.StatusSucceeded, LastTransitionTime: metav1.NewTime(time.Now().Add(-time.Hour))}},
+	}
+
+	c.Enqueue(wfr)
+
+	if c.queue.Len() != 1 {
+		t.Fatalf("expected terminated WorkflowRun with a TTL to be queued, queue len = %d", c.queue.Len())
+	}
+}
+
+func TestEnqueueSkipsNonTerminatedRun(t *testing.T) {
+	c := NewController(nil, nil)
+
+	wfr := &v1alpha1.WorkflowRun{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "run-1"},
+		Spec:       v1alpha1.WorkflowRunSpec{TTLStrategy: &v1alpha1.TTLStrategy{SecondsAfterCompletion: int64Ptr(60)}},
+		Status:     v1alpha1.WorkflowRunStatus{Overall: v1alpha1.Status{Phase: v1alpha1.StatusRunning}},
+	}
+
+	c.Enqueue(wfr)
+
+	if c.queue.Len() != 0 {
+		t.Fatalf("expected a still-running WorkflowRun not to be queued, queue len = %d", c.queue.Len())
+	}
+}
+
+func TestExpiresAfterRequiresTermination(t *testing.T) {
+	wfr := &v1alpha1.WorkflowRun{
+		Spec:   v1alpha1.WorkflowRunSpec{TTLStrategy: &v1alpha1.TTLStrategy{SecondsAfterCompletion: int64Ptr(1)}},
+		Status: v1alpha1.WorkflowRunStatus{Overall: v1alpha1.Status{Phase: v1alpha1.StatusRunning}},
+	}
+
+	if _, ok := expiresAfter(wfr); ok {
+		t.Fatal("expected expiresAfter to report false for a non-terminated WorkflowRun")
+	}
+}
+
+func TestExpiresAfterNoTTLConfiguredAnywhere(t *testing.T) {
+	loadTTLDefaults(t, controller.TTLDefaults{})
+
+	wfr := &v1alpha1.WorkflowRun{
+		Status: v1alpha1.WorkflowRunStatus{Overall: v1alpha1.Status{
+			Phase:              v1alpha1.StatusSucceeded,
+			LastTransitionTime: metav1.NewTime(time.Unix(1000, 0)),
+		}},
+	}
+
+	if _, ok := expiresAfter(wfr); ok {
+		t.Fatal("expected expiresAfter to report false when neither spec nor cluster defaults configure a TTL")
+	}
+}
+
+func TestTTLSecondsOwnCompletionOverridesEverything(t *testing.T) {
+	loadTTLDefaults(t, controller.TTLDefaults{SecondsAfterCompletion: int64Ptr(999)})
+
+	wfr := &v1alpha1.WorkflowRun{
+		Spec: v1alpha1.WorkflowRunSpec{TTLStrategy: &v1alpha1.TTLStrategy{
+			SecondsAfterCompletion: int64Ptr(30),
+			SecondsAfterSuccess:    int64Ptr(10),
+		}},
+		Status: v1alpha1.WorkflowRunStatus{Overall: v1alpha1.Status{Phase: v1alpha1.StatusSucceeded}},
+	}
+
+	got := ttlSeconds(wfr)
+	if got == nil || *got != 30 {
+		t.Fatalf("expected spec-level SecondsAfterCompletion (30) to win, got %v", got)
+	}
+}
+
+func TestTTLSecondsOwnOutcomeSpecificBeatsClusterDefaults(t *testing.T) {
+	loadTTLDefaults(t, controller.TTLDefaults{SecondsAfterCompletion: int64Ptr(999)})
+
+	wfr := &v1alpha1.WorkflowRun{
+		Spec:   v1alpha1.WorkflowRunSpec{TTLStrategy: &v1alpha1.TTLStrategy{SecondsAfterSuccess: int64Ptr(10)}},
+		Status: v1alpha1.WorkflowRunStatus{Overall: v1alpha1.Status{Phase: v1alpha1.StatusSucceeded}},
+	}
+
+	got := ttlSeconds(wfr)
+	if got == nil || *got != 10 {
+		t.Fatalf("expected spec-level SecondsAfterSuccess (10) to beat cluster SecondsAfterCompletion, got %v", got)
+	}
+}
+
+func TestTTLSecondsFallsBackToClusterDefaults(t *testing.T) {
+	loadTTLDefaults(t, controller.TTLDefaults{SecondsAfterFailure: int64Ptr(42)})
+
+	wfr := &v1alpha1.WorkflowRun{
+		Status: v1alpha1.WorkflowRunStatus{Overall: v1alpha1.Status{Phase: v1alpha1.StatusFailed}},
+	}
+
+	got := ttlSeconds(wfr)
+	if got == nil || *got != 42 {
+		t.Fatalf("expected cluster-wide SecondsAfterFailure default to apply with no spec strategy, got %v", got)
+	}
+}
+
+func TestExpiresAfterComputesAbsoluteTime(t *testing.T) {
+	finishedAt := time.Unix(1000, 0)
+	wfr := &v1alpha1.WorkflowRun{
+		Spec: v1alpha1.WorkflowRunSpec{TTLStrategy: &v1alpha1.TTLStrategy{SecondsAfterCompletion: int64Ptr(30)}},
+		Status: v1alpha1.WorkflowRunStatus{Overall: v1alpha1.Status{
+			Phase:              v1alpha1.StatusSucceeded,
+			LastTransitionTime: metav1.NewTime(finishedAt),
+		}},
+	}
+
+	expiry, ok := expiresAfter(wfr)
+	if !ok {
+		t.Fatal("expected a TTL to apply")
+	}
+	want := finishedAt.Add(30 * time.Second)
+	if !expiry.Equal(want) {
+		t.Fatalf("expected expiry %v, got %v", want, expiry)
+	}
+}
+
+func TestSplitKey(t *testing.T) {
+	cases := map[string][2]string{
+		"ns/name": {"ns", "name"},
+		"name":    {"", "name"},
+	}
+	for key, want := range cases {
+		ns, name := splitKey(key)
+		if ns != want[0] || name != want[1] {
+			t.Fatalf("splitKey(%q) = (%q, %q), want (%q, %q)", key, ns, name, want[0], want[1])
+		}
+	}
+}